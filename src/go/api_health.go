@@ -6,12 +6,27 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-type HealthAPI struct{}
+// HealthAPI reports process health, including the payment broker's state
+// (see paymentbroker.go) so load-test tooling can distinguish "healthy" from
+// "shedding load" without guessing from response codes alone.
+type HealthAPI struct {
+	broker *paymentBroker // optional; nil omits payment_broker from the response
+}
+
+// NewHealthAPI builds a HealthAPI that reports broker's state. Pass nil if
+// the process doesn't run OrdersAPI.
+func NewHealthAPI(broker *paymentBroker) *HealthAPI {
+	return &HealthAPI{broker: broker}
+}
 
 // GET /health
 func (api *HealthAPI) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
+	resp := gin.H{
 		"status":  "ok",
 		"message": "service healthy",
-	})
+	}
+	if api.broker != nil {
+		resp["payment_broker"] = api.broker.Status()
+	}
+	c.JSON(http.StatusOK, resp)
 }
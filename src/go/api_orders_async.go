@@ -1,42 +1,50 @@
 package api
 
 import (
-	"context"
 	"encoding/json"
+	"log"
 	"net/http"
-	"os"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	sns "github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/gin-gonic/gin"
 )
 
 type OrdersAsyncAPI struct {
-	sns      *sns.Client // AWS SNS client used to publish messages
-	topicArn string      // ARN of the SNS topic for order events
+	publisher Publisher // async transport; see publisher.go (SNS/Kafka/memory)
 }
 
-// NewOrdersAsyncAPI initializes a new OrdersAsyncAPI instance.
-// It loads AWS credentials/configuration from the default provider chain (local, EC2, or Fargate).
+// NewOrdersAsyncAPI initializes a new OrdersAsyncAPI instance, picking its
+// transport from ASYNC_BACKEND (sns|kafka|memory, default sns). See
+// publisher.go for the backend selection and each implementation.
 func NewOrdersAsyncAPI() *OrdersAsyncAPI {
-	cfg, _ := config.LoadDefaultConfig(context.Background())
-	return &OrdersAsyncAPI{
-		sns:      sns.NewFromConfig(cfg),
-		topicArn: os.Getenv("SNS_TOPIC_ARN"), // e.g. arn:aws:sns:us-east-1:123456789012:order-processing-events
+	publisher, err := NewPublisherFromEnv()
+	if err != nil {
+		// Match the prior behavior of loading AWS config best-effort at
+		// startup: log and fall back to a topic-less SNS publisher so the
+		// process still comes up; every publish then fails with
+		// errMissingTopicArn, and the original error is only logged here.
+		// Built directly rather than via
+		// newSNSPublisher(), which would just hit the same config error
+		// again and return a nil *snsPublisher — wrapped in the Publisher
+		// interface, that's a non-nil interface with a nil receiver, and
+		// Publish would panic on p.topicArn instead of returning
+		// errMissingTopicArn.
+		log.Printf("NewOrdersAsyncAPI: %v; falling back to sns backend", err)
+		publisher = &snsPublisher{}
 	}
+	return &OrdersAsyncAPI{publisher: publisher}
 }
 
 // OrdersAsync handles POST /orders/async.
 // Instead of blocking to process payments synchronously,
-// this endpoint immediately publishes the order to an SNS topic
-// so it can be processed asynchronously by downstream workers.
+// this endpoint immediately publishes the order to the configured async
+// transport (SNS, Kafka, or an in-memory stub in tests) so it can be
+// processed asynchronously by downstream workers.
 //
 // Flow:
 //  1. Parse the incoming JSON request.
 //  2. Set order status = "received" and timestamp.
-//  3. Publish order data as JSON to SNS.
+//  3. Publish order data as JSON, keyed by order_id for per-order ordering.
 //  4. Return HTTP 202 Accepted to the client immediately.
 func (a *OrdersAsyncAPI) OrdersAsync(c *gin.Context) {
 	var order Order
@@ -50,25 +58,15 @@ func (a *OrdersAsyncAPI) OrdersAsync(c *gin.Context) {
 	order.Status = "received"
 	order.CreatedAt = time.Now()
 
-	// Ensure SNS topic is configured
-	if a.topicArn == "" {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "missing_topic_arn"})
-		return
-	}
-
 	// Marshal order struct into JSON message
 	body, _ := json.Marshal(order)
 
-	// Publish to SNS (non-blocking for clients)
-	_, err := a.sns.Publish(
-		c.Request.Context(), // Reuse HTTP request context for tracing/cancellation
-		&sns.PublishInput{
-			TopicArn: aws.String(a.topicArn),
-			Message:  aws.String(string(body)),
-		},
-	)
+	// Publish via the configured transport (non-blocking for clients).
+	// key = order_id so Kafka partitions (and therefore ordering) are
+	// per-order; SNS/memory publishers ignore it.
+	err := a.publisher.Publish(c.Request.Context(), order.OrderID, body)
 	if err != nil {
-		// Return error if SNS publish fails
+		// Return error if publish fails
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "publish_failed", "detail": err.Error()})
 		return
 	}
@@ -77,6 +75,6 @@ func (a *OrdersAsyncAPI) OrdersAsync(c *gin.Context) {
 	c.JSON(http.StatusAccepted, gin.H{
 		"status":   "accepted",
 		"order_id": order.OrderID,
-		"note":     "Queued for processing via SNS/SQS",
+		"note":     "Queued for processing via the configured async transport",
 	})
 }
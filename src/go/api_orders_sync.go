@@ -1,10 +1,16 @@
 package api
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Item Structure
@@ -23,27 +29,77 @@ type Order struct {
 	CreatedAt  time.Time `json:"created_at"`
 }
 
-// Buffered channel: A buffer size of 5 means a maximum of 5 orders can be processed at the same time
-// Simulating a Payment Bottleneck
-var paymentGate = make(chan struct{}, 5)
+// Simulates a synchronous payment verification: it takes 3 seconds to
+// complete. Concurrency is bounded by the caller (paymentBroker's gate),
+// not by this function. Honors ctx's deadline/cancellation (paymentBroker.Do
+// bounds every attempt with PAYMENT_VERIFY_TIMEOUT_MS), returning an error
+// instead of the simulated result so a stuck or slow downstream actually
+// counts as a failure against the circuit breaker.
+func verifyPaymentSync(ctx context.Context) error {
+	_, span := tracer.Start(ctx, "payment.verify", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
 
-// Simulates a synchronous payment verification: It takes 3 seconds to complete and is limited by paymentGate concurrency.
-func verifyPaymentSync() {
-	paymentGate <- struct{}{}        // occupy one "payment slot"
-	defer func() { <-paymentGate }() // release the slot when done
-	time.Sleep(3 * time.Second)      // simulate 3-second processing delay
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		time.Sleep(3 * time.Second) // simulate 3-second processing delay
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		paymentVerifySeconds.Observe(time.Since(start).Seconds())
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
 // POST: /v1/orders/sync
 // This endpoint demonstrates synchronous order processing:
 //  1. Receive an order
-//  2. Verify payment (3s delay)
+//  2. Verify payment (3s delay), subject to paymentBroker's bounded queue
+//     and circuit breaker
 //  3. Return 200 OK after completion
+//
+// Under load, paymentBroker sheds work instead of letting every handler
+// block forever:
+//   - 202, if the request was shed to the async transport as a fallback
+//     (only when OrdersAPI was built with one configured)
+//   - 429, if the wait queue is already full
+//   - 503 + Retry-After, if the circuit breaker is open
+//   - 504, if the request's own deadline expires while queued
+type OrdersAPI struct {
+	broker   *paymentBroker
+	fallback Publisher // optional; nil disables the async-shed path
+}
+
+// NewOrdersAPI builds an OrdersAPI backed by a paymentBroker sized from
+// PAYMENT_GATE_CAPACITY/PAYMENT_QUEUE_LIMIT (defaults 5/20, matching the
+// original fixed-size paymentGate). If ASYNC_FALLBACK=true, shed requests
+// are published via the same transport OrdersAsyncAPI uses instead of
+// simply failing.
+func NewOrdersAPI() *OrdersAPI {
+	broker := newPaymentBroker(
+		envIntOr("PAYMENT_GATE_CAPACITY", 5),
+		envIntOr("PAYMENT_QUEUE_LIMIT", 20),
+	)
+
+	var fallback Publisher
+	if os.Getenv("ASYNC_FALLBACK") == "true" {
+		if p, err := NewPublisherFromEnv(); err == nil {
+			fallback = p
+		}
+	}
+
+	return &OrdersAPI{broker: broker, fallback: fallback}
+}
 
-type OrdersAPI struct{}
+// Broker exposes the payment broker so NewHealthAPI can report its state.
+func (a *OrdersAPI) Broker() *paymentBroker { return a.broker }
 
 // OrdersSync handles a synchronous order request.
-func (OrdersAPI) OrdersSync(c *gin.Context) {
+func (a *OrdersAPI) OrdersSync(c *gin.Context) {
 	var req Order
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -57,7 +113,13 @@ func (OrdersAPI) OrdersSync(c *gin.Context) {
 	req.Status = "processing"
 	start := time.Now()
 
-	verifyPaymentSync()
+	err := a.broker.Do(c.Request.Context(), func(ctx context.Context) error {
+		return verifyPaymentSync(ctx)
+	})
+	if err != nil {
+		a.shed(c, req, err)
+		return
+	}
 	lat := time.Since(start)
 
 	req.Status = "completed"
@@ -68,3 +130,35 @@ func (OrdersAPI) OrdersSync(c *gin.Context) {
 	// Return the completed order
 	c.JSON(http.StatusOK, req)
 }
+
+// shed responds to a request the broker declined to run, falling back to
+// the async transport when one is configured instead of just failing.
+func (a *OrdersAPI) shed(c *gin.Context, req Order, err error) {
+	if a.fallback != nil && (errors.Is(err, ErrQueueFull) || errors.Is(err, ErrBreakerOpen)) {
+		req.Status = "received"
+		body, marshalErr := json.Marshal(req)
+		if marshalErr == nil {
+			if pubErr := a.fallback.Publish(c.Request.Context(), req.OrderID, body); pubErr == nil {
+				c.JSON(http.StatusAccepted, gin.H{
+					"status":   "accepted",
+					"order_id": req.OrderID,
+					"note":     "shed to async fallback under payment backpressure",
+				})
+				return
+			}
+		}
+	}
+
+	switch {
+	case errors.Is(err, ErrQueueFull):
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "queue_full"})
+	case errors.Is(err, ErrBreakerOpen):
+		c.Header("Retry-After", strconv.Itoa(int(a.broker.RetryAfter().Seconds())))
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "breaker_open"})
+	case errors.Is(err, ErrDeadlineExceeded):
+		c.JSON(http.StatusGatewayTimeout, gin.H{"error": "deadline_exceeded"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -101,27 +101,37 @@ func InitSearchData(n int) {
 	// Reset cursors/counters.
 	scanCursor.Store(0)
 	totalChecked.Store(0)
+
+	// Build the inverted index used by mode=ranked (see api_products_search_bm25.go).
+	buildInvertedIndex()
 }
 
 //
 // ----------------------------- HTTP API -------------------------------
 //
-// GET /v1/products/search?q=<term>&limit=<n>
+// GET /v1/products/search?q=<term>&limit=<n>&mode=<window|ranked>
 //
 // Behavior:
 // - Validates query param q (required), limit ∈ (1..20], default 20.
-// - Scans a fixed window of 100 items starting from a moving base offset.
+// - mode=window (default): scans a fixed window of 100 items starting from a
+//   moving base offset.
 //   * base is computed by scanCursor.Add(window)-window so each request
 //     starts at a different segment (round-robin), then wrapped by modulo.
-// - Matches if q is a case-insensitive substring of Name or Category.
-// - Returns up to `limit` matched products, but always scans up to 100 items.
+//   * Matches if q is a case-insensitive substring of Name or Category.
+//   * Returns up to `limit` matched products, but always scans up to 100 items.
+//   * Kept as-is for load-test parity: cost is independent of corpus/query.
+// - mode=ranked: tokenizes q, walks the inverted index built by
+//   buildInvertedIndex (see api_products_search_bm25.go), and scores
+//   candidates with BM25. See that file for scoring details.
 // - Responds with observability fields for load testing:
-//   * checked: how many items were examined this request (≈100)
-//   * total_checked: cumulative items examined (across all requests)
-//   * window_start/window_size: scan parameters
+//   * checked/posting_hits: how many items were examined this request
+//   * total_checked: cumulative items examined (across all requests, window mode)
+//   * window_start/window_size: scan parameters (window mode)
+//   * indexed_terms: distinct query tokens found in the index (ranked mode)
 //   * took_ms: elapsed time of this search (ms)
 //
-// Complexity per request: O(window) = O(100) -> stable CPU cost.
+// Complexity per request: O(window) = O(100) for window mode; O(sum of
+// matching posting lists) for ranked mode.
 //
 
 // SearchProducts handles the fixed-cost search endpoint.
@@ -145,6 +155,11 @@ func (api *ProductsAPI) SearchProducts(c *gin.Context) {
 		}
 	}
 
+	if c.Query("mode") == "ranked" {
+		api.searchProductsRanked(c, q, limit)
+		return
+	}
+
 	start := time.Now()
 
 	// If data is not initialized yet, return an empty result with metrics.
@@ -191,6 +206,7 @@ func (api *ProductsAPI) SearchProducts(c *gin.Context) {
 
 	// Update global cumulative metric (atomic & contention-free).
 	totalChecked.Add(uint64(checked))
+	searchScanItems.Observe(float64(checked))
 
 	// --- 4) Respond with results + observability fields ---
 	resp := gin.H{
@@ -0,0 +1,173 @@
+// This file adds a real (non-fixed-cost) search path alongside the
+// window-scan behavior in api_products_search.go: a case-folded inverted
+// index plus BM25 ranking, exposed via GET /v1/products/search?mode=ranked.
+//
+// The window-scan endpoint stays untouched so existing load-test baselines
+// are unaffected; this is purely an additive code path.
+package api
+
+import (
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BM25 tuning constants (standard defaults; see Robertson & Zaragoza).
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// invertedIdx maps a token to the sorted list of product IDs containing it.
+// docTermFreq[id][token] is the number of occurrences of token in doc id.
+// docLength[id] is the total token count of doc id (Name + Category + Brand + Description).
+var (
+	indexMu      sync.RWMutex
+	invertedIdx  map[string][]int
+	docTermFreq  map[int]map[string]int
+	docLength    map[int]int
+	avgDocLength float64
+)
+
+// tokenize lower-cases and splits on anything that isn't an ASCII letter or digit.
+func tokenize(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+}
+
+// buildInvertedIndex rebuilds the token -> posting-list index from the
+// current contents of searchStore/idIndex. Called once from InitSearchData;
+// not safe to call concurrently with itself.
+func buildInvertedIndex() {
+	idx := make(map[string][]int)
+	tf := make(map[int]map[string]int)
+	dl := make(map[int]int)
+
+	var totalLen int
+	for _, id := range idIndex {
+		v, ok := searchStore.Load(id)
+		if !ok {
+			continue
+		}
+		p := v.(SearchProduct)
+
+		tokens := tokenize(p.Name + " " + p.Category + " " + p.Brand + " " + p.Description)
+		dl[id] = len(tokens)
+		totalLen += len(tokens)
+
+		counts := make(map[string]int, len(tokens))
+		for _, t := range tokens {
+			counts[t]++
+		}
+		for t, n := range counts {
+			idx[t] = append(idx[t], id)
+			if tf[id] == nil {
+				tf[id] = make(map[string]int)
+			}
+			tf[id][t] = n
+		}
+	}
+	for t := range idx {
+		sort.Ints(idx[t])
+	}
+
+	indexMu.Lock()
+	invertedIdx = idx
+	docTermFreq = tf
+	docLength = dl
+	if len(idIndex) > 0 {
+		avgDocLength = float64(totalLen) / float64(len(idIndex))
+	} else {
+		avgDocLength = 0
+	}
+	indexMu.Unlock()
+}
+
+// bm25Score scores doc `id` against the query tokens using per-token IDF
+// (computed against the corpus size N) and the standard BM25 term-frequency
+// saturation/length-normalization formula.
+func bm25Score(id int, tokens []string, df map[string]int, n int) float64 {
+	if n == 0 || avgDocLength == 0 {
+		return 0
+	}
+	dl := float64(docLength[id])
+	var score float64
+	for _, t := range tokens {
+		tf := float64(docTermFreq[id][t])
+		if tf == 0 {
+			continue
+		}
+		d := float64(df[t])
+		idf := math.Log((float64(n)-d+0.5)/(d+0.5) + 1)
+		score += idf * tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*dl/avgDocLength))
+	}
+	return score
+}
+
+// searchProductsRanked implements mode=ranked: tokenize q, union the
+// per-token posting lists into a candidate set, score each candidate with
+// BM25, and return the top `limit` by score.
+func (api *ProductsAPI) searchProductsRanked(c *gin.Context, q string, limit int) {
+	start := time.Now()
+
+	tokens := tokenize(q)
+
+	indexMu.RLock()
+	n := len(idIndex)
+
+	df := make(map[string]int, len(tokens))
+	candidates := make(map[int]struct{})
+	postingHits := 0
+	indexedTerms := 0
+	for _, t := range tokens {
+		if _, dup := df[t]; dup {
+			continue // repeated query token: already counted
+		}
+		postings, ok := invertedIdx[t]
+		if !ok {
+			continue
+		}
+		indexedTerms++
+		df[t] = len(postings)
+		postingHits += len(postings)
+		for _, id := range postings {
+			candidates[id] = struct{}{}
+		}
+	}
+
+	scored := make([]SearchProduct, 0, len(candidates))
+	scores := make(map[int]float64, len(candidates))
+	for id := range candidates {
+		v, ok := searchStore.Load(id)
+		if !ok {
+			continue
+		}
+		scores[id] = bm25Score(id, tokens, df, n)
+		scored = append(scored, v.(SearchProduct))
+	}
+	indexMu.RUnlock()
+
+	searchScanItems.Observe(float64(postingHits))
+
+	sort.Slice(scored, func(i, j int) bool {
+		return scores[scored[i].ID] > scores[scored[j].ID]
+	})
+	if len(scored) > limit {
+		scored = scored[:limit]
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"hits":          scored,
+		"count":         len(scored),
+		"mode":          "ranked",
+		"indexed_terms": indexedTerms,
+		"posting_hits":  postingHits,
+		"took_ms":       time.Since(start).Milliseconds(),
+	})
+}
@@ -0,0 +1,53 @@
+package api
+
+import "testing"
+
+func TestTokenizeLowercasesAndSplitsOnNonAlnum(t *testing.T) {
+	got := tokenize("Red T-Shirt, Size-42!")
+	want := []string{"red", "t", "shirt", "size", "42"}
+	if len(got) != len(want) {
+		t.Fatalf("tokenize() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("tokenize() = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestBuildInvertedIndexAndBM25ScoreRankRelevantDocHigher(t *testing.T) {
+	idIndex = []int{901, 902}
+	searchStore.Store(901, SearchProduct{
+		ID: 901, Name: "red running shoes", Category: "footwear",
+		Brand: "Acme", Description: "lightweight red running shoes for daily training",
+	})
+	searchStore.Store(902, SearchProduct{
+		ID: 902, Name: "blue winter jacket", Category: "outerwear",
+		Brand: "Acme", Description: "warm jacket for cold weather",
+	})
+
+	buildInvertedIndex()
+
+	tokens := tokenize("red running shoes")
+	df := make(map[string]int, len(tokens))
+	for _, tok := range tokens {
+		df[tok] = len(invertedIdx[tok])
+	}
+
+	relevant := bm25Score(901, tokens, df, len(idIndex))
+	unrelated := bm25Score(902, tokens, df, len(idIndex))
+	if relevant <= unrelated {
+		t.Fatalf("bm25Score(901)=%v should outscore bm25Score(902)=%v for query %q", relevant, unrelated, tokens)
+	}
+}
+
+func TestBM25ScoreZeroWithoutIndex(t *testing.T) {
+	idIndex = nil
+	searchStore.Delete(901)
+	searchStore.Delete(902)
+	buildInvertedIndex()
+
+	if got := bm25Score(1, []string{"anything"}, map[string]int{"anything": 0}, 0); got != 0 {
+		t.Errorf("bm25Score() with empty corpus = %v, want 0", got)
+	}
+}
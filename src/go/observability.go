@@ -0,0 +1,115 @@
+// This file wires OpenTelemetry tracing and Prometheus metrics into every
+// api handler. A server span is started per request by Middleware; outbound
+// calls (SNS publish) start their own client spans and are linked by the
+// W3C trace context carried in SNS MessageAttributes, so a single trace
+// covers POST /v1/orders/async -> sns.Publish -> SQS receive ->
+// the payment.verify span of attemptDelivery on the worker side.
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/TomatoesSuck/CS6650_2b_demo/api"
+
+var tracer = otel.Tracer(tracerName)
+
+var (
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http_request_duration_seconds",
+		Help:    "Latency of api HTTP handlers.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	searchScanItems = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "search_scan_items",
+		Help:    "Number of items examined per SearchProducts request.",
+		Buckets: []float64{10, 50, 100, 250, 500, 1000, 5000},
+	})
+
+	paymentVerifySeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "payment_verify_seconds",
+		Help:    "Latency of the synchronous payment verification call.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	publishLatencySeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "publish_latency_seconds",
+		Help:    "Latency of publishing an order to the async transport.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"backend"})
+)
+
+// InitObservability configures the global OpenTelemetry tracer provider with
+// an OTLP/gRPC exporter (endpoint from OTEL_EXPORTER_OTLP_ENDPOINT, as with
+// any standard OTel SDK) and a W3C tracecontext propagator. Call once at
+// process startup; the returned func flushes and shuts the provider down.
+func InitObservability(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return tp.Shutdown, nil
+}
+
+// Middleware starts a server span and an http_request_duration_seconds
+// observation for every request. Register with router.Use(api.Middleware()).
+func Middleware() gin.HandlerFunc {
+	propagator := otel.GetTextMapPropagator()
+	return func(c *gin.Context) {
+		ctx := propagator.Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracer.Start(ctx, c.Request.Method+" "+c.FullPath(), trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		start := time.Now()
+
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(attribute.Int("http.status_code", status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+
+		httpRequestDuration.WithLabelValues(c.FullPath(), c.Request.Method, http.StatusText(status)).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// MetricsHandler exposes the process's Prometheus metrics. Register with
+// router.GET("/metrics", api.MetricsHandler()).
+func MetricsHandler() gin.HandlerFunc {
+	h := promhttp.Handler()
+	return func(c *gin.Context) { h.ServeHTTP(c.Writer, c.Request) }
+}
+
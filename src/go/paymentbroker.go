@@ -0,0 +1,216 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Errors returned by paymentBroker.Do, distinguishing the three ways a
+// request can be shed instead of completing.
+var (
+	ErrQueueFull        = errors.New("payment queue full")
+	ErrBreakerOpen      = errors.New("payment circuit breaker open")
+	ErrDeadlineExceeded = errors.New("deadline exceeded waiting for a payment slot")
+)
+
+type breakerState int32
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half_open"
+	default:
+		return "closed"
+	}
+}
+
+// paymentBroker wraps the payment concurrency limit (gate) and adds
+// two things load-test tooling (and real clients) can observe:
+//   - a bounded FIFO wait queue, so a burst sheds load with 429 instead of
+//     every handler piling up behind the gate forever;
+//   - a circuit breaker that opens once the failure/timeout ratio over a
+//     rolling window crosses a threshold, so a struggling payment backend
+//     fails fast with 503 instead of every caller waiting out their deadline.
+type paymentBroker struct {
+	gate chan struct{}
+
+	queueLimit int32
+	queued     int32 // requests inside Do (waiting for or holding a gate slot), atomically bounded by queueLimit
+
+	mu            sync.Mutex
+	state         breakerState
+	outcomes      []bool // ring buffer of recent successes(true)/failures(false)
+	next          int
+	openedAt      time.Time
+	consecutiveOK int
+
+	windowSize       int
+	failureThreshold float64
+	cooldown         time.Duration
+	closeAfterOK     int
+
+	verifyTimeout time.Duration
+}
+
+func newPaymentBroker(capacity, queueLimit int) *paymentBroker {
+	return &paymentBroker{
+		gate:             make(chan struct{}, capacity),
+		queueLimit:       int32(queueLimit),
+		outcomes:         make([]bool, 0, 20),
+		windowSize:       20,
+		failureThreshold: 0.5,
+		cooldown:         10 * time.Second,
+		closeAfterOK:     3,
+		verifyTimeout:    time.Duration(envIntOr("PAYMENT_VERIFY_TIMEOUT_MS", 5000)) * time.Millisecond,
+	}
+}
+
+// Do runs fn once a payment slot is available, subject to the queue limit,
+// the circuit breaker, and ctx's deadline. fn is additionally bounded by
+// verifyTimeout, so a downstream that's merely slow (not canceled) still
+// counts as a failure instead of blocking its caller indefinitely. fn's
+// success/failure feeds back into the breaker.
+func (b *paymentBroker) Do(ctx context.Context, fn func(context.Context) error) error {
+	if b.breakerOpen() {
+		return ErrBreakerOpen
+	}
+
+	if atomic.AddInt32(&b.queued, 1) > b.queueLimit {
+		atomic.AddInt32(&b.queued, -1)
+		return ErrQueueFull
+	}
+	defer atomic.AddInt32(&b.queued, -1)
+
+	select {
+	case b.gate <- struct{}{}:
+	case <-ctx.Done():
+		return ErrDeadlineExceeded
+	}
+	defer func() { <-b.gate }()
+
+	attemptCtx, cancel := context.WithTimeout(ctx, b.verifyTimeout)
+	defer cancel()
+
+	err := fn(attemptCtx)
+	b.record(err == nil)
+	return err
+}
+
+// breakerOpen reports whether requests should currently fast-fail, moving
+// an expired open breaker into half-open. Half-open admits every caller;
+// record re-opens the breaker on the first failure and closes it after
+// closeAfterOK consecutive successes.
+func (b *paymentBroker) breakerOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerOpen && time.Since(b.openedAt) >= b.cooldown {
+		b.state = breakerHalfOpen
+	}
+	return b.state == breakerOpen
+}
+
+// record feeds one outcome into the rolling window and opens/closes the
+// breaker accordingly.
+func (b *paymentBroker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.outcomes) < b.windowSize {
+		b.outcomes = append(b.outcomes, success)
+	} else {
+		b.outcomes[b.next] = success
+		b.next = (b.next + 1) % b.windowSize
+	}
+
+	if b.state == breakerHalfOpen {
+		if !success {
+			b.state = breakerOpen
+			b.openedAt = time.Now()
+			b.consecutiveOK = 0
+			return
+		}
+		b.consecutiveOK++
+		if b.consecutiveOK >= b.closeAfterOK {
+			b.state = breakerClosed
+			b.consecutiveOK = 0
+		}
+		return
+	}
+
+	if success {
+		return
+	}
+	if len(b.outcomes) < b.windowSize || b.failureRate() < b.failureThreshold {
+		return
+	}
+	b.state = breakerOpen
+	b.openedAt = time.Now()
+}
+
+// failureRate must be called with b.mu held.
+func (b *paymentBroker) failureRate() float64 {
+	failures := 0
+	for _, ok := range b.outcomes {
+		if !ok {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(b.outcomes))
+}
+
+// RetryAfter is the Retry-After (seconds) to send a caller shed by an open
+// breaker: whatever's left of the cooldown, plus a little jitter so every
+// client doesn't retry in the same instant.
+func (b *paymentBroker) RetryAfter() time.Duration {
+	b.mu.Lock()
+	remaining := b.cooldown - time.Since(b.openedAt)
+	b.mu.Unlock()
+
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining + time.Duration(rand.Int63n(int64(time.Second)))
+}
+
+// Status reports broker state for /health.
+type PaymentBrokerStatus struct {
+	State      string `json:"state"`
+	QueueDepth int32  `json:"queue_depth"`
+	QueueLimit int32  `json:"queue_limit"`
+}
+
+func (b *paymentBroker) Status() PaymentBrokerStatus {
+	b.mu.Lock()
+	state := b.state
+	b.mu.Unlock()
+
+	return PaymentBrokerStatus{
+		State:      state.String(),
+		QueueDepth: atomic.LoadInt32(&b.queued),
+		QueueLimit: b.queueLimit,
+	}
+}
+
+func envIntOr(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func newTestBroker(capacity, queueLimit, windowSize int, failureThreshold float64, cooldown time.Duration) *paymentBroker {
+	return &paymentBroker{
+		gate:             make(chan struct{}, capacity),
+		queueLimit:       int32(queueLimit),
+		outcomes:         make([]bool, 0, windowSize),
+		windowSize:       windowSize,
+		failureThreshold: failureThreshold,
+		cooldown:         cooldown,
+		closeAfterOK:     1,
+		verifyTimeout:    time.Second,
+	}
+}
+
+func TestPaymentBrokerOpensAfterConsecutiveFailures(t *testing.T) {
+	b := newTestBroker(5, 20, 4, 0.5, time.Minute)
+	failing := errors.New("boom")
+
+	for i := 0; i < 4; i++ {
+		if err := b.Do(context.Background(), func(ctx context.Context) error { return failing }); !errors.Is(err, failing) {
+			t.Fatalf("attempt %d: Do() = %v, want %v", i, err, failing)
+		}
+	}
+
+	var called bool
+	err := b.Do(context.Background(), func(ctx context.Context) error { called = true; return nil })
+	if !errors.Is(err, ErrBreakerOpen) {
+		t.Fatalf("Do() after 4 straight failures = %v, want ErrBreakerOpen", err)
+	}
+	if called {
+		t.Error("Do() invoked fn while the breaker is open")
+	}
+}
+
+func TestPaymentBrokerHalfOpenClosesAfterSuccess(t *testing.T) {
+	b := newTestBroker(5, 20, 4, 0.5, 10*time.Millisecond)
+	failing := errors.New("boom")
+	for i := 0; i < 4; i++ {
+		_ = b.Do(context.Background(), func(ctx context.Context) error { return failing })
+	}
+	if got := b.Status().State; got != "open" {
+		t.Fatalf("Status().State = %q, want %q after 4 straight failures", got, "open")
+	}
+
+	time.Sleep(20 * time.Millisecond) // let the breaker's cooldown elapse
+
+	if err := b.Do(context.Background(), func(ctx context.Context) error { return nil }); err != nil {
+		t.Fatalf("Do() on the first half-open attempt = %v, want nil", err)
+	}
+	if got := b.Status().State; got != "closed" {
+		t.Errorf("Status().State = %q, want %q after a successful half-open attempt", got, "closed")
+	}
+}
+
+func TestPaymentBrokerRejectsWhenQueueFull(t *testing.T) {
+	b := newTestBroker(1, 1, 20, 0.5, time.Minute)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- b.Do(context.Background(), func(ctx context.Context) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	if err := b.Do(context.Background(), func(ctx context.Context) error { return nil }); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Do() while the gate is saturated = %v, want ErrQueueFull", err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("held Do() = %v, want nil", err)
+	}
+}
@@ -0,0 +1,31 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// Publisher abstracts the async transport used by OrdersAsyncAPI to hand an
+// order off to downstream workers. key is used for partitioning/ordering by
+// implementations that support it (e.g. the Kafka partition key); it is
+// ignored by implementations that don't (e.g. SNS).
+type Publisher interface {
+	Publish(ctx context.Context, key string, payload []byte) error
+	Close() error
+}
+
+// NewPublisherFromEnv selects a Publisher implementation based on
+// ASYNC_BACKEND (sns|kafka|memory, default sns).
+func NewPublisherFromEnv() (Publisher, error) {
+	switch backend := os.Getenv("ASYNC_BACKEND"); backend {
+	case "", "sns":
+		return newSNSPublisher()
+	case "kafka":
+		return newKafkaPublisherFromEnv()
+	case "memory":
+		return newMemoryPublisher(), nil
+	default:
+		return nil, fmt.Errorf("publisher: unknown ASYNC_BACKEND %q", backend)
+	}
+}
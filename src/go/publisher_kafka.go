@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// kafkaPublisher publishes order events to a Kafka topic. It batches writes
+// in a bounded in-memory buffer that the underlying kafka.Writer flushes on
+// either the batch size or the flush interval, whichever comes first.
+//
+// Partitioning defaults to order_id so all events for one order (and, since
+// order_id is unique, effectively one customer flow) land on the same
+// partition and are read back in order.
+type kafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+func newKafkaPublisherFromEnv() (*kafkaPublisher, error) {
+	brokers := strings.Split(os.Getenv("KAFKA_BROKERS"), ",") // e.g. "broker1:9092,broker2:9092"
+	topic := os.Getenv("KAFKA_TOPIC")
+
+	batchSize := 100
+	if v := os.Getenv("KAFKA_BATCH_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			batchSize = n
+		}
+	}
+	flushInterval := time.Second
+	if v := os.Getenv("KAFKA_FLUSH_INTERVAL_MS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			flushInterval = time.Duration(n) * time.Millisecond
+		}
+	}
+
+	return &kafkaPublisher{
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{}, // key-based partitioning (order_id -> partition)
+			RequiredAcks: kafka.RequireAll,
+			// Async: false makes WriteMessages block until RequiredAcks is
+			// satisfied, so a nil error means every in-sync replica durably
+			// has the message. kafka-go has no idempotent-producer support
+			// (no producer ID/sequence numbers), so a Publish retried after
+			// a timeout can still double-write; consumers that need
+			// exactly-once should dedupe on order_id.
+			Async:        false,
+			BatchSize:    batchSize,
+			BatchTimeout: flushInterval,
+		},
+	}, nil
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, key string, payload []byte) error {
+	ctx, span := tracer.Start(ctx, "kafka.Publish", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	start := time.Now()
+	defer func() {
+		publishLatencySeconds.WithLabelValues("kafka").Observe(time.Since(start).Seconds())
+	}()
+
+	msg := kafka.Message{
+		Key:   []byte(key), // default: order_id, for per-order/customer ordering
+		Value: payload,
+	}
+	otel.GetTextMapPropagator().Inject(ctx, &kafkaHeaderCarrier{msg: &msg})
+
+	return p.writer.WriteMessages(ctx, msg)
+}
+
+// kafkaHeaderCarrier adapts kafka.Message.Headers to otel's
+// propagation.TextMapCarrier.
+type kafkaHeaderCarrier struct {
+	msg *kafka.Message
+}
+
+func (c *kafkaHeaderCarrier) Get(key string) string {
+	for _, h := range c.msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c *kafkaHeaderCarrier) Set(key, value string) {
+	c.msg.Headers = append(c.msg.Headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c *kafkaHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.msg.Headers))
+	for _, h := range c.msg.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+var _ propagation.TextMapCarrier = (*kafkaHeaderCarrier)(nil)
+
+func (p *kafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"sync"
+)
+
+// memoryMessage is one record captured by a memoryPublisher.
+type memoryMessage struct {
+	Key     string
+	Payload []byte
+}
+
+// memoryPublisher is an in-process Publisher backed by a slice guarded by a
+// mutex. It's used for ASYNC_BACKEND=memory and in tests that want to assert
+// on what OrdersAsyncAPI published without standing up SNS or Kafka.
+type memoryPublisher struct {
+	mu       sync.Mutex
+	messages []memoryMessage
+}
+
+func newMemoryPublisher() *memoryPublisher {
+	return &memoryPublisher{}
+}
+
+func (p *memoryPublisher) Publish(_ context.Context, key string, payload []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.messages = append(p.messages, memoryMessage{Key: key, Payload: payload})
+	return nil
+}
+
+func (p *memoryPublisher) Close() error { return nil }
+
+// Messages returns a copy of everything published so far, in order.
+func (p *memoryPublisher) Messages() []memoryMessage {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]memoryMessage, len(p.messages))
+	copy(out, p.messages)
+	return out
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	sns "github.com/aws/aws-sdk-go-v2/service/sns"
+	snstypes "github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// errMissingTopicArn is returned by Publish when SNS_TOPIC_ARN was not set.
+var errMissingTopicArn = errors.New("missing_topic_arn")
+
+// snsPublisher publishes to a single SNS topic. This is the original
+// transport OrdersAsyncAPI used before Publisher was introduced.
+type snsPublisher struct {
+	client   *sns.Client
+	topicArn string
+}
+
+func newSNSPublisher() (*snsPublisher, error) {
+	cfg, err := config.LoadDefaultConfig(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &snsPublisher{
+		client:   sns.NewFromConfig(cfg),
+		topicArn: os.Getenv("SNS_TOPIC_ARN"), // e.g. arn:aws:sns:us-east-1:123456789012:order-processing-events
+	}, nil
+}
+
+func (p *snsPublisher) Publish(ctx context.Context, key string, payload []byte) error {
+	ctx, span := tracer.Start(ctx, "sns.Publish", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("http.method", "POST"),
+		attribute.String("http.url", p.topicArn),
+	)
+
+	start := time.Now()
+	defer func() {
+		publishLatencySeconds.WithLabelValues("sns").Observe(time.Since(start).Seconds())
+	}()
+
+	if p.topicArn == "" {
+		return errMissingTopicArn
+	}
+
+	// Carry the trace context along on the SNS envelope so the worker can
+	// extract it on the SQS side and continue the same trace (requires the
+	// subscription's RawMessageDelivery to be enabled).
+	attrs := make(map[string]snstypes.MessageAttributeValue)
+	otel.GetTextMapPropagator().Inject(ctx, &snsAttributeCarrier{attrs: attrs})
+
+	_, err := p.client.Publish(ctx, &sns.PublishInput{
+		TopicArn:          aws.String(p.topicArn),
+		Message:           aws.String(string(payload)),
+		MessageAttributes: attrs,
+	})
+	return err
+}
+
+func (p *snsPublisher) Close() error { return nil }
+
+// snsAttributeCarrier adapts SNS MessageAttributes to otel's
+// propagation.TextMapCarrier so the tracecontext propagator can inject into
+// it directly.
+type snsAttributeCarrier struct {
+	attrs map[string]snstypes.MessageAttributeValue
+}
+
+func (c *snsAttributeCarrier) Get(key string) string {
+	if v, ok := c.attrs[key]; ok {
+		return aws.ToString(v.StringValue)
+	}
+	return ""
+}
+
+func (c *snsAttributeCarrier) Set(key, value string) {
+	c.attrs[key] = snstypes.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(value),
+	}
+}
+
+func (c *snsAttributeCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.attrs))
+	for k := range c.attrs {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"time"
+)
+
+// Message is one unit of work pulled off the queue, independent of whether
+// it came from SQS or Kafka.
+type Message struct {
+	Body string
+
+	// ReceiveCount is how many times this message has been delivered,
+	// including this time (SQS: ApproximateReceiveCount; Kafka: always 1,
+	// since a consumer group has no equivalent counter).
+	ReceiveCount int
+
+	// Ctx carries the trace context extracted from the message's transport
+	// envelope (SQS MessageAttributes / Kafka headers), so a span started
+	// while processing this message continues the trace from
+	// POST /v1/orders/async -> sns.Publish. Never nil; defaults to
+	// context.Background() when no trace context was present.
+	Ctx context.Context
+
+	Ack  func(context.Context) error // delete (SQS) / commit (Kafka) on success
+	Nack func(context.Context) error // best-effort: return to queue / skip commit
+
+	// Extend, if non-nil, asks the transport to push back the point at
+	// which this message becomes visible to other consumers again (SQS
+	// ChangeMessageVisibility). Kafka consumers leave this nil; a consumer
+	// group has no equivalent and relies on session.timeout.ms instead.
+	Extend func(ctx context.Context, timeout time.Duration) error
+}
+
+// Consumer abstracts the inbound transport for the worker so the processing
+// pipeline (see delivery.go) can run unchanged against SQS or Kafka,
+// matching the Publisher side in api.NewOrdersAsyncAPI (ASYNC_BACKEND).
+type Consumer interface {
+	// Receive blocks (long-polling where supported) until at least one
+	// message is available, ctx is canceled, or an error occurs.
+	Receive(ctx context.Context) ([]Message, error)
+	Close() error
+}
+
+// newConsumerFromEnv selects a Consumer implementation based on
+// ASYNC_BACKEND (sns|kafka|memory, default sns). "sns" and "memory" both
+// consume from SQS: SNS fans out to an SQS queue, and the in-process memory
+// publisher is only meaningful in tests that talk to SQS directly via a
+// mocked client.
+func newConsumerFromEnv() (Consumer, error) {
+	switch backend := getenv("ASYNC_BACKEND", "sns"); backend {
+	case "sns", "memory":
+		return newSQSConsumerFromEnv()
+	case "kafka":
+		return newKafkaConsumerFromEnv()
+	default:
+		return nil, errUnknownBackend(backend)
+	}
+}
@@ -0,0 +1,462 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math/rand"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Delivery fans payment-verification attempts out to a fixed pool of
+// persistent processor goroutines, one channel per processor. Jobs are
+// routed by orderKey (hash(orderKey) % processors), so every job for a
+// given key is always handled by the same goroutine and therefore
+// processed in order; jobs for different keys run fully in parallel.
+// Failures are tracked per target (shard) so a slow or failing downstream
+// only throttles its own shard instead of starving everyone, the way the
+// single global `gate` used to.
+//
+// Neither transport is exactly-once (SNS/SQS and kafka-go are both
+// at-least-once), so Delivery also dedupes by order_id: a redelivered
+// message for an order_id already verified within dedupeTTL is acked
+// without charging the customer twice.
+type Delivery struct {
+	queues []chan deliveryJob
+	wg     sync.WaitGroup
+	states sync.Map // target (string) -> *targetState
+
+	// mu guards closed, and is held across every Submit so a backed-off
+	// job's delayed time.AfterFunc retry (see run) can never send on a
+	// queue after Close has closed it.
+	mu     sync.RWMutex
+	closed bool
+
+	seen sync.Map // order_id (string) -> time.Time of last successful delivery
+	stop chan struct{}
+}
+
+// deliveryJob is one pending payment-verification attempt.
+type deliveryJob struct {
+	target   string // backoff/failure-isolation bucket (see shardFor)
+	orderKey string // ordering bucket (see orderKeyFor); same key -> same processor
+	msg      Message
+	order    Order
+}
+
+const (
+	deliveryQueueSize = 1000
+
+	// Exponential backoff for a target whose error rate has crossed the
+	// threshold: 1s, 2s, 4s, ... capped at 5m, plus jitter.
+	backoffBase = time.Second
+	backoffMax  = 5 * time.Minute
+
+	// A target opens its backoff once at least failureWindowMin attempts
+	// have been observed and the failure rate over the last
+	// failureWindowSize attempts reaches failureRateThreshold.
+	failureWindowSize    = 20
+	failureWindowMin     = 5
+	failureRateThreshold = 0.5
+
+	// Per-attempt deadline; a payment verification that doesn't finish in
+	// time counts as a failure against its target.
+	defaultAttemptTimeout = 5 * time.Second
+
+	// Default number of times a message may be received before the
+	// delivery subsystem gives up on it instead of retrying forever.
+	defaultMaxReceives = 5
+
+	// SQS visibility timeout set on receive (see sqs_consumer.go); the
+	// heartbeat refreshes it well before it would otherwise expire so a
+	// slow payment verification doesn't make the message visible again
+	// mid-processing.
+	sqsVisibilityTimeout = 30 * time.Second
+	heartbeatInterval    = 20 * time.Second
+
+	// How long a completed order_id is remembered so a duplicate delivery
+	// (redelivery after a slow ack, a Kafka rebalance replaying an
+	// uncommitted offset, etc.) is recognized and skipped instead of
+	// re-charging the customer.
+	dedupeTTL = 10 * time.Minute
+)
+
+var (
+	deliveryQueueDepth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "delivery_queue_depth",
+		Help: "Pending delivery attempts waiting for a worker, by target.",
+	}, []string{"target"})
+
+	deliveryInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "delivery_in_flight",
+		Help: "Delivery attempts currently executing across all targets.",
+	})
+
+	deliveryDuplicatesSkipped = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "delivery_duplicates_skipped_total",
+		Help: "Deliveries acked without reprocessing because their order_id was already completed within dedupeTTL.",
+	})
+
+	deliveryTargetBackoff = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "delivery_target_backoff",
+		Help: "1 if the target is currently in its backoff window, else 0.",
+	}, []string{"target"})
+)
+
+// NewDelivery starts `processors` persistent goroutines, each bound to its
+// own queue, and returns the handle used to submit jobs.
+func NewDelivery(processors int) *Delivery {
+	if processors < 1 {
+		processors = 1
+	}
+	d := &Delivery{queues: make([]chan deliveryJob, processors), stop: make(chan struct{})}
+	for i := range d.queues {
+		d.queues[i] = make(chan deliveryJob, deliveryQueueSize)
+		d.wg.Add(1)
+		go d.run(d.queues[i])
+	}
+	go d.sweepDedupe()
+	return d
+}
+
+// Submit enqueues a delivery attempt. Jobs sharing an orderKey always land
+// on the same processor queue, so they're handled in order; keys are spread
+// across all processor queues by hash. Blocks if that queue is
+// full, applying natural back-pressure to the receive loop. A Submit that
+// loses the race with Close (e.g. a delayed backoff retry firing during
+// shutdown) nacks the job instead of sending on a closed channel; it will be
+// redelivered and re-submitted after the process restarts.
+func (d *Delivery) Submit(job deliveryJob) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		_ = job.msg.Nack(context.Background())
+		return
+	}
+
+	idx := processorFor(job.orderKey, len(d.queues))
+	deliveryQueueDepth.WithLabelValues(job.target).Inc()
+	d.queues[idx] <- job
+}
+
+// Close stops accepting new work and blocks until every queued job has been
+// taken off its queue and every in-flight attempt has finished, for a
+// graceful shutdown. A queued job whose target is backed off is rescheduled
+// rather than attempted, and its retry is nacked by Submit once Close has run.
+func (d *Delivery) Close() {
+	d.mu.Lock()
+	d.closed = true
+	for _, q := range d.queues {
+		close(q)
+	}
+	d.mu.Unlock()
+	d.wg.Wait()
+	close(d.stop)
+}
+
+// sweepDedupe periodically evicts entries from seen older than dedupeTTL, so
+// a long-running worker's dedupe set stays bounded by order volume over one
+// TTL window rather than growing forever.
+func (d *Delivery) sweepDedupe() {
+	ticker := time.NewTicker(dedupeTTL)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			d.seen.Range(func(k, v interface{}) bool {
+				if now.Sub(v.(time.Time)) > dedupeTTL {
+					d.seen.Delete(k)
+				}
+				return true
+			})
+		case <-d.stop:
+			return
+		}
+	}
+}
+
+// alreadyDelivered reports whether orderID was successfully delivered within
+// the last dedupeTTL, evicting it (and reporting false) once that window has
+// passed.
+func (d *Delivery) alreadyDelivered(orderID string) bool {
+	v, ok := d.seen.Load(orderID)
+	if !ok {
+		return false
+	}
+	if time.Since(v.(time.Time)) > dedupeTTL {
+		d.seen.Delete(orderID)
+		return false
+	}
+	return true
+}
+
+// markDelivered records orderID as successfully delivered just now, so a
+// redelivery of the same message within dedupeTTL is recognized as a
+// duplicate instead of re-verifying payment.
+func (d *Delivery) markDelivered(orderID string) {
+	d.seen.Store(orderID, time.Now())
+}
+
+// processorFor deterministically maps an ordering key to one of n
+// processors via FNV-1a, so the same key always routes to the same
+// goroutine.
+func processorFor(orderKey string, n int) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(orderKey))
+	return int(h.Sum32()) % n
+}
+
+func (d *Delivery) run(jobs <-chan deliveryJob) {
+	defer d.wg.Done()
+
+	for job := range jobs {
+		deliveryQueueDepth.WithLabelValues(job.target).Dec()
+		ctx := context.Background()
+
+		if job.msg.ReceiveCount > maxReceives() {
+			log.Printf("giving up on order=%s target=%s after %d receives (dead-letter)", job.order.OrderID, job.target, job.msg.ReceiveCount)
+			_ = job.msg.Ack(ctx) // drop it: Ack deletes (SQS) / commits (Kafka), so it is not redelivered
+			continue
+		}
+
+		if d.alreadyDelivered(job.order.OrderID) {
+			deliveryDuplicatesSkipped.Inc()
+			log.Printf("skipping duplicate delivery order=%s target=%s (already verified within %s)", job.order.OrderID, job.target, dedupeTTL)
+			_ = job.msg.Ack(ctx)
+			continue
+		}
+
+		state := d.stateFor(job.target)
+		if wait := state.waitTime(); wait > 0 {
+			// Reschedule rather than sleeping this goroutine: a processor
+			// is shared by every orderKey hashed onto it (see processorFor),
+			// so blocking here for up to backoffMax would also stall every
+			// other key's delivery until the backed-off target recovers.
+			time.AfterFunc(wait, func() { d.Submit(job) })
+			continue
+		}
+
+		stopHeartbeat := startVisibilityHeartbeat(job.msg)
+		err := attemptDelivery(job.msg.Ctx, job.order)
+		stopHeartbeat()
+		state.record(err == nil)
+
+		if err != nil {
+			log.Printf("delivery failed target=%s order=%s err=%v", job.target, job.order.OrderID, err)
+			// Leave the message un-acked; it's redelivered (SQS visibility
+			// timeout expiry / no Kafka commit) and re-submitted to this
+			// same target, by which point the backoff above will apply.
+			_ = job.msg.Nack(ctx)
+			continue
+		}
+
+		job.order.Status = "completed"
+		d.markDelivered(job.order.OrderID)
+		log.Printf("Processed order=%s target=%s", job.order.OrderID, job.target)
+
+		if err := job.msg.Ack(ctx); err != nil {
+			log.Printf("ack failed: %v", err)
+		}
+	}
+}
+
+// maxReceives is how many times a message may be received before it's
+// dead-lettered instead of retried, configurable via MAX_RECEIVES.
+func maxReceives() int {
+	if v := os.Getenv("MAX_RECEIVES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultMaxReceives
+}
+
+// startVisibilityHeartbeat periodically extends msg's visibility timeout
+// while it's being processed, so a long payment verification doesn't let
+// SQS redeliver it to another worker mid-flight. No-op for transports (e.g.
+// Kafka) that don't support per-message visibility extension.
+func startVisibilityHeartbeat(msg Message) func() {
+	if msg.Extend == nil {
+		return func() {}
+	}
+	stop := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(heartbeatInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := msg.Extend(context.Background(), sqsVisibilityTimeout); err != nil {
+					log.Printf("visibility extend failed: %v", err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return func() { close(stop) }
+}
+
+// orderKeyFor extracts the field named by ORDER_KEY (default customer_id)
+// so all messages for that key route to the same processor and stay
+// ordered. Only the fields present on Order are supported.
+func orderKeyFor(order Order) string {
+	switch os.Getenv("ORDER_KEY") {
+	case "order_id":
+		return order.OrderID
+	default: // "customer_id" and unset
+		return strconv.Itoa(order.CustomerID)
+	}
+}
+
+// attemptDelivery runs the payment verification under a deadline so a stuck
+// downstream call is observable as a failure instead of hanging a worker
+// forever. If traceCtx carries a trace (extracted from the inbound
+// message), the payment.verify span continues that trace. Each target's
+// attempts are isolated by targetState's per-target backoff; there is no
+// shared concurrency gate across targets, so one saturated target can't
+// starve delivery to any other.
+func attemptDelivery(traceCtx context.Context, order Order) error {
+	if traceCtx == nil {
+		traceCtx = context.Background()
+	}
+
+	ctx, cancel := context.WithTimeout(traceCtx, attemptTimeout())
+	defer cancel()
+
+	_, span := tracer.Start(ctx, "payment.verify", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	deliveryInFlight.Inc()
+	defer deliveryInFlight.Dec()
+
+	start := time.Now()
+	done := make(chan struct{})
+	go func() {
+		time.Sleep(3 * time.Second) // simulate payment-provider round trip
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		paymentVerifySeconds.Observe(time.Since(start).Seconds())
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("order=%s: %w", order.OrderID, ctx.Err())
+	}
+}
+
+func attemptTimeout() time.Duration {
+	if v := os.Getenv("PAYMENT_TIMEOUT_MS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return time.Duration(n) * time.Millisecond
+		}
+	}
+	return defaultAttemptTimeout
+}
+
+// shardFor buckets an order into a delivery target. Real payment providers
+// would key on provider host; absent that here, customer shard gives the
+// same "isolate a noisy neighbor" property.
+func shardFor(order Order) string {
+	shards := 8
+	if v := os.Getenv("DELIVERY_SHARDS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			shards = n
+		}
+	}
+	return fmt.Sprintf("shard-%d", order.CustomerID%shards)
+}
+
+// targetState tracks recent outcomes and the current backoff window for
+// one delivery target.
+type targetState struct {
+	mu         sync.Mutex
+	target     string
+	outcomes   []bool // ring buffer of recent successes(true)/failures(false)
+	next       int
+	failStreak int       // consecutive target-wide failures, drives exponential delay
+	until      time.Time // backoff expires at this time; zero value means not backing off
+}
+
+func (d *Delivery) stateFor(target string) *targetState {
+	if v, ok := d.states.Load(target); ok {
+		return v.(*targetState)
+	}
+	st := &targetState{target: target, outcomes: make([]bool, 0, failureWindowSize)}
+	actual, _ := d.states.LoadOrStore(target, st)
+	return actual.(*targetState)
+}
+
+// waitTime returns how long the caller should sleep before attempting a
+// delivery to this target, based on whether it's currently backed off.
+func (s *targetState) waitTime() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.until.IsZero() || time.Now().After(s.until) {
+		return 0
+	}
+	return time.Until(s.until)
+}
+
+// record tracks the outcome of one attempt and opens/extends or closes this
+// target's backoff window accordingly.
+func (s *targetState) record(success bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.outcomes) < failureWindowSize {
+		s.outcomes = append(s.outcomes, success)
+	} else {
+		s.outcomes[s.next] = success
+		s.next = (s.next + 1) % failureWindowSize
+	}
+
+	if success {
+		s.failStreak = 0
+		s.until = time.Time{}
+		deliveryTargetBackoff.WithLabelValues(s.target).Set(0)
+		return
+	}
+
+	if len(s.outcomes) < failureWindowMin || s.failureRate() < failureRateThreshold {
+		return
+	}
+
+	s.failStreak++
+	delay := backoffBase
+	for i := 1; i < s.failStreak && delay < backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > backoffMax {
+		delay = backoffMax
+	}
+	delay += time.Duration(rand.Int63n(int64(delay) / 4 + 1)) // jitter, up to +25%
+	s.until = time.Now().Add(delay)
+	deliveryTargetBackoff.WithLabelValues(s.target).Set(1)
+}
+
+// failureRate must be called with s.mu held.
+func (s *targetState) failureRate() float64 {
+	if len(s.outcomes) == 0 {
+		return 0
+	}
+	failures := 0
+	for _, ok := range s.outcomes {
+		if !ok {
+			failures++
+		}
+	}
+	return float64(failures) / float64(len(s.outcomes))
+}
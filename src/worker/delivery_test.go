@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestProcessorForIsStablePerKey(t *testing.T) {
+	const n = 8
+	for _, key := range []string{"1", "42", "customer-7"} {
+		want := processorFor(key, n)
+		for i := 0; i < 5; i++ {
+			if got := processorFor(key, n); got != want {
+				t.Fatalf("processorFor(%q) = %d, want stable %d", key, got, want)
+			}
+		}
+	}
+}
+
+func TestOrderKeyForDefaultsToCustomerID(t *testing.T) {
+	order := Order{OrderID: "o1", CustomerID: 42}
+	if got := orderKeyFor(order); got != "42" {
+		t.Errorf("orderKeyFor() = %q, want %q", got, "42")
+	}
+}
+
+func TestDeliveryDedupesRedeliveredOrderID(t *testing.T) {
+	d := &Delivery{}
+
+	if d.alreadyDelivered("o1") {
+		t.Fatal("alreadyDelivered() = true before any delivery was marked")
+	}
+
+	d.markDelivered("o1")
+	if !d.alreadyDelivered("o1") {
+		t.Fatal("alreadyDelivered() = false right after markDelivered()")
+	}
+	if d.alreadyDelivered("o2") {
+		t.Fatal("alreadyDelivered() = true for an order_id that was never delivered")
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/segmentio/kafka-go"
+	"go.opentelemetry.io/otel"
+)
+
+// kafkaConsumer backs ASYNC_BACKEND=kafka, mirroring the partitioning/key
+// scheme of the kafka publisher in the api package (order_id as key).
+type kafkaConsumer struct {
+	reader *kafka.Reader
+}
+
+func newKafkaConsumerFromEnv() (*kafkaConsumer, error) {
+	brokers := strings.Split(os.Getenv("KAFKA_BROKERS"), ",")
+	topic := os.Getenv("KAFKA_TOPIC")
+	groupID := getenv("KAFKA_GROUP_ID", "orders-worker")
+
+	return &kafkaConsumer{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers: brokers,
+			Topic:   topic,
+			GroupID: groupID,
+		}),
+	}, nil
+}
+
+func (c *kafkaConsumer) Receive(ctx context.Context) ([]Message, error) {
+	m, err := c.reader.FetchMessage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	msg := m
+	traceCtx := otel.GetTextMapPropagator().Extract(context.Background(), &kafkaReceivedHeaderCarrier{headers: msg.Headers})
+
+	return []Message{{
+		Body:         string(msg.Value),
+		ReceiveCount: 1, // consumer groups have no delivery counter to expose
+		Ctx:          traceCtx,
+		Ack: func(ctx context.Context) error {
+			return c.reader.CommitMessages(ctx, msg)
+		},
+		Nack: func(ctx context.Context) error {
+			// Don't commit; the next Fetch from this group re-delivers it.
+			return nil
+		},
+		// Extend left nil: Kafka consumer groups rely on session.timeout.ms,
+		// not a per-message visibility timeout.
+	}}, nil
+}
+
+func (c *kafkaConsumer) Close() error {
+	return c.reader.Close()
+}
+
+// kafkaReceivedHeaderCarrier adapts an already-fetched kafka.Message's
+// Headers to otel's propagation.TextMapCarrier for extraction.
+type kafkaReceivedHeaderCarrier struct {
+	headers []kafka.Header
+}
+
+func (c *kafkaReceivedHeaderCarrier) Get(key string) string {
+	for _, h := range c.headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c *kafkaReceivedHeaderCarrier) Set(key, value string) {
+	c.headers = append(c.headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c *kafkaReceivedHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.headers))
+	for _, h := range c.headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
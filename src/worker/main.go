@@ -2,18 +2,13 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"fmt"
 	"log"
 	"os"
-	"strconv"
 	"time"
-
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	sqs "github.com/aws/aws-sdk-go-v2/service/sqs"
 )
 
-// Item and Order represent the message structure stored in SQS
+// Item and Order represent the message structure stored in SQS/Kafka.
 type Item struct {
 	SKU string `json:"sku"`
 	Qty int    `json:"qty"`
@@ -27,82 +22,45 @@ type Order struct {
 	CreatedAt  time.Time `json:"created_at"`
 }
 
-// gate limits the maximum concurrent payment processing
-var gate chan struct{}
+// getenv returns os.Getenv(key), or def if unset/empty.
+func getenv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
 
-// Simulate a 3-second payment verification
-func verifyPaymentSync() {
-	gate <- struct{}{}
-	defer func() { <-gate }()
-	time.Sleep(3 * time.Second)
+func errUnknownBackend(backend string) error {
+	return fmt.Errorf("worker: unknown ASYNC_BACKEND %q", backend)
 }
 
 func main() {
-	// Load environment variables
-	region := os.Getenv("AWS_REGION")
-	queueURL := os.Getenv("SQS_QUEUE_URL")
-	cc := 10
-	if v := os.Getenv("WORKER_CONCURRENCY"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 {
-			cc = n
-		}
+	shutdownTracing, err := initObservability(context.Background(), "orders-worker")
+	if err != nil {
+		log.Printf("tracing disabled: %v", err)
+	} else {
+		defer shutdownTracing(context.Background())
 	}
-	gate = make(chan struct{}, cc)
+	serveMetrics()
 
-	// Initialize AWS SQS client
-	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region))
+	cc := envInt("WORKER_CONCURRENCY", 10)
+
+	// Pick the inbound transport (sns -> SQS, kafka -> Kafka) to match
+	// whichever ASYNC_BACKEND the api process is publishing with.
+	consumer, err := newConsumerFromEnv()
 	if err != nil {
 		log.Fatal(err)
 	}
-	client := sqs.NewFromConfig(cfg)
-
-	log.Printf("Worker started; queue=%s cc=%d\n", queueURL, cc)
+	defer consumer.Close()
 
-	// Main polling loop
-	for {
-		out, err := client.ReceiveMessage(context.Background(), &sqs.ReceiveMessageInput{
-			QueueUrl:            aws.String(queueURL),
-			MaxNumberOfMessages: 10,
-			WaitTimeSeconds:     20,
-			VisibilityTimeout:   30,
-		})
-		if err != nil {
-			log.Printf("receive error: %v", err)
-			time.Sleep(time.Second)
-			continue
-		}
-		if len(out.Messages) == 0 {
-			continue
-		}
+	processors := envInt("WORKER_PROCESSORS", cc)
+	receivers := envInt("WORKER_RECEIVERS", 4)
+	delivery := NewDelivery(processors)
 
-		// Process each message concurrently
-		for _, m := range out.Messages {
-			msg := m
-			go func() {
-				var order Order
-				if err := json.Unmarshal([]byte(*msg.Body), &order); err != nil {
-					log.Printf("bad message: %v", err)
-					// Delete malformed message
-					_, _ = client.DeleteMessage(context.Background(), &sqs.DeleteMessageInput{
-						QueueUrl:      aws.String(queueURL),
-						ReceiptHandle: msg.ReceiptHandle,
-					})
-					return
-				}
+	log.Printf("Worker started; backend=%s receivers=%d processors=%d\n", getenv("ASYNC_BACKEND", "sns"), receivers, processors)
 
-				verifyPaymentSync()
-				order.Status = "completed"
-				log.Printf("Processed order=%s", order.OrderID)
+	// Run until SIGTERM/SIGINT, then drain in-flight work before exiting.
+	runOrchestrator(shutdownContext(), consumer, delivery, receivers)
 
-				// Delete message after successful processing
-				_, err := client.DeleteMessage(context.Background(), &sqs.DeleteMessageInput{
-					QueueUrl:      aws.String(queueURL),
-					ReceiptHandle: msg.ReceiptHandle,
-				})
-				if err != nil {
-					log.Printf("delete failed: %v", err)
-				}
-			}()
-		}
-	}
+	log.Println("worker shut down cleanly")
 }
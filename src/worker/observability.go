@@ -0,0 +1,90 @@
+// This file wires OpenTelemetry tracing and Prometheus metrics into the
+// worker: extracting the trace context carried on SQS/Kafka messages so a
+// trace started at POST /v1/orders/async continues through
+// attemptDelivery's payment.verify span, plus a /metrics endpoint alongside the delivery
+// subsystem's own gauges (see delivery.go).
+package main
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+const tracerName = "github.com/TomatoesSuck/CS6650_2b_demo/worker"
+
+var tracer = otel.Tracer(tracerName)
+
+var (
+	paymentVerifySeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "payment_verify_seconds",
+		Help:    "Latency of the worker's payment verification call.",
+		Buckets: prometheus.DefBuckets,
+	})
+
+	sqsReceiveBatchSize = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "sqs_receive_batch_size",
+		Help:    "Number of messages returned per SQS ReceiveMessage call.",
+		Buckets: []float64{0, 1, 2, 5, 10},
+	})
+)
+
+// initObservability mirrors api.InitObservability: an OTLP/gRPC exporter
+// configured via the standard OTEL_EXPORTER_OTLP_ENDPOINT env var and a W3C
+// tracecontext propagator, so span IDs extracted off SQS/Kafka messages
+// link up with the spans the api process started.
+func initObservability(ctx context.Context, serviceName string) (func(context.Context) error, error) {
+	exporter, err := otlptracegrpc.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx, resource.WithAttributes(semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return tp.Shutdown, nil
+}
+
+// serveMetrics starts a best-effort /metrics listener on METRICS_ADDR
+// (default :9090). The worker has no HTTP surface otherwise, so this is a
+// plain net/http server rather than piggybacking on gin.
+func serveMetrics() {
+	addr := os.Getenv("METRICS_ADDR")
+	if addr == "" {
+		addr = ":9090"
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		log.Printf("metrics listener disabled: %v", err)
+		return
+	}
+	go func() {
+		if err := http.Serve(ln, mux); err != nil {
+			log.Printf("metrics server stopped: %v", err)
+		}
+	}()
+}
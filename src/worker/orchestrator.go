@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// runOrchestrator starts `receivers` goroutines long-polling consumer and
+// handing parsed orders off to delivery, until ctx is canceled. It blocks
+// until every receiver has stopped and delivery has drained its queues, so
+// a SIGTERM finishes in-flight work before the process exits.
+func runOrchestrator(ctx context.Context, consumer Consumer, delivery *Delivery, receivers int) {
+	var wg sync.WaitGroup
+	wg.Add(receivers)
+	for i := 0; i < receivers; i++ {
+		go func() {
+			defer wg.Done()
+			receiveLoop(ctx, consumer, delivery)
+		}()
+	}
+	wg.Wait()
+	delivery.Close()
+}
+
+// receiveLoop is one receiver goroutine: long-poll, unmarshal, route to the
+// delivery subsystem. Malformed bodies are dropped immediately rather than
+// retried forever.
+func receiveLoop(ctx context.Context, consumer Consumer, delivery *Delivery) {
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		msgs, err := consumer.Receive(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("receive error: %v", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		for _, m := range msgs {
+			var order Order
+			if err := json.Unmarshal([]byte(m.Body), &order); err != nil {
+				log.Printf("bad message: %v", err)
+				_ = m.Ack(context.Background())
+				continue
+			}
+
+			delivery.Submit(deliveryJob{
+				target:   shardFor(order),
+				orderKey: orderKeyFor(order),
+				msg:      m,
+				order:    order,
+			})
+		}
+	}
+}
+
+// shutdownContext returns a context canceled on SIGTERM/SIGINT, so
+// runOrchestrator stops issuing new receives and drains in-flight work
+// instead of being killed mid-delivery.
+func shutdownContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGTERM, syscall.SIGINT)
+	go func() {
+		<-sigCh
+		log.Println("shutdown signal received; draining in-flight deliveries")
+		cancel()
+	}()
+	return ctx
+}
+
+func envInt(key string, def int) int {
+	if v := os.Getenv(key); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return def
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	sqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"go.opentelemetry.io/otel"
+)
+
+// sqsAPI is the subset of *sqs.Client the consumer depends on, narrowed so
+// tests can substitute a mock.
+type sqsAPI interface {
+	ReceiveMessage(ctx context.Context, in *sqs.ReceiveMessageInput, opts ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error)
+	DeleteMessage(ctx context.Context, in *sqs.DeleteMessageInput, opts ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error)
+	ChangeMessageVisibility(ctx context.Context, in *sqs.ChangeMessageVisibilityInput, opts ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error)
+}
+
+// sqsConsumer is the original transport: long-polling SQS ReceiveMessage,
+// deleting on success. It backs ASYNC_BACKEND=sns and ASYNC_BACKEND=memory,
+// since SNS fans out to an SQS queue that the worker still reads from.
+type sqsConsumer struct {
+	client   sqsAPI
+	queueURL string
+}
+
+func newSQSConsumerFromEnv() (*sqsConsumer, error) {
+	region := os.Getenv("AWS_REGION")
+	cfg, err := config.LoadDefaultConfig(context.Background(), config.WithRegion(region))
+	if err != nil {
+		return nil, err
+	}
+	return &sqsConsumer{
+		client:   sqs.NewFromConfig(cfg),
+		queueURL: os.Getenv("SQS_QUEUE_URL"),
+	}, nil
+}
+
+func (c *sqsConsumer) Receive(ctx context.Context) ([]Message, error) {
+	out, err := c.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+		QueueUrl:            aws.String(c.queueURL),
+		MaxNumberOfMessages: 10,
+		WaitTimeSeconds:     20,
+		VisibilityTimeout:   30,
+		MessageSystemAttributeNames: []sqstypes.MessageSystemAttributeName{
+			sqstypes.MessageSystemAttributeNameApproximateReceiveCount,
+		},
+		MessageAttributeNames: []string{"All"}, // needed to see the injected trace context
+	})
+	if err != nil {
+		return nil, err
+	}
+	sqsReceiveBatchSize.Observe(float64(len(out.Messages)))
+
+	msgs := make([]Message, 0, len(out.Messages))
+	for _, m := range out.Messages {
+		m := m
+
+		receiveCount := 1
+		if v, ok := m.Attributes[string(sqstypes.MessageSystemAttributeNameApproximateReceiveCount)]; ok {
+			if n, err := strconv.Atoi(v); err == nil {
+				receiveCount = n
+			}
+		}
+
+		traceCtx := otel.GetTextMapPropagator().Extract(context.Background(), &sqsAttributeCarrier{attrs: m.MessageAttributes})
+
+		msgs = append(msgs, Message{
+			Body:         aws.ToString(m.Body),
+			ReceiveCount: receiveCount,
+			Ctx:          traceCtx,
+			Ack: func(ctx context.Context) error {
+				_, err := c.client.DeleteMessage(ctx, &sqs.DeleteMessageInput{
+					QueueUrl:      aws.String(c.queueURL),
+					ReceiptHandle: m.ReceiptHandle,
+				})
+				return err
+			},
+			Nack: func(ctx context.Context) error {
+				// Leave the message in-flight; it reappears once the
+				// visibility timeout expires.
+				return nil
+			},
+			Extend: func(ctx context.Context, timeout time.Duration) error {
+				_, err := c.client.ChangeMessageVisibility(ctx, &sqs.ChangeMessageVisibilityInput{
+					QueueUrl:          aws.String(c.queueURL),
+					ReceiptHandle:     m.ReceiptHandle,
+					VisibilityTimeout: int32(timeout.Seconds()),
+				})
+				return err
+			},
+		})
+	}
+	return msgs, nil
+}
+
+func (c *sqsConsumer) Close() error { return nil }
+
+// sqsAttributeCarrier adapts SQS MessageAttributes to otel's
+// propagation.TextMapCarrier for extraction. Only Get/Keys are needed here;
+// the publisher side (api.snsAttributeCarrier) handles injection.
+type sqsAttributeCarrier struct {
+	attrs map[string]sqstypes.MessageAttributeValue
+}
+
+func (c *sqsAttributeCarrier) Get(key string) string {
+	if v, ok := c.attrs[key]; ok {
+		return aws.ToString(v.StringValue)
+	}
+	return ""
+}
+
+func (c *sqsAttributeCarrier) Set(key, value string) {
+	c.attrs[key] = sqstypes.MessageAttributeValue{
+		DataType:    aws.String("String"),
+		StringValue: aws.String(value),
+	}
+}
+
+func (c *sqsAttributeCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.attrs))
+	for k := range c.attrs {
+		keys = append(keys, k)
+	}
+	return keys
+}
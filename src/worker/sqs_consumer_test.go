@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	sqs "github.com/aws/aws-sdk-go-v2/service/sqs"
+	sqstypes "github.com/aws/aws-sdk-go-v2/service/sqs/types"
+)
+
+// fakeSQS is a minimal mock of sqsAPI for exercising sqsConsumer without a
+// real queue.
+type fakeSQS struct {
+	receiveOut *sqs.ReceiveMessageOutput
+	deleted    []string
+	extended   []string
+}
+
+func (f *fakeSQS) ReceiveMessage(ctx context.Context, in *sqs.ReceiveMessageInput, opts ...func(*sqs.Options)) (*sqs.ReceiveMessageOutput, error) {
+	return f.receiveOut, nil
+}
+
+func (f *fakeSQS) DeleteMessage(ctx context.Context, in *sqs.DeleteMessageInput, opts ...func(*sqs.Options)) (*sqs.DeleteMessageOutput, error) {
+	f.deleted = append(f.deleted, *in.ReceiptHandle)
+	return &sqs.DeleteMessageOutput{}, nil
+}
+
+func (f *fakeSQS) ChangeMessageVisibility(ctx context.Context, in *sqs.ChangeMessageVisibilityInput, opts ...func(*sqs.Options)) (*sqs.ChangeMessageVisibilityOutput, error) {
+	f.extended = append(f.extended, *in.ReceiptHandle)
+	return &sqs.ChangeMessageVisibilityOutput{}, nil
+}
+
+func TestSQSConsumerReceiveParsesReceiveCount(t *testing.T) {
+	body := `{"order_id":"o1","customer_id":1}`
+	receipt := "receipt-1"
+	fake := &fakeSQS{
+		receiveOut: &sqs.ReceiveMessageOutput{
+			Messages: []sqstypes.Message{{
+				Body:          &body,
+				ReceiptHandle: &receipt,
+				Attributes: map[string]string{
+					string(sqstypes.MessageSystemAttributeNameApproximateReceiveCount): "3",
+				},
+			}},
+		},
+	}
+	c := &sqsConsumer{client: fake, queueURL: "q"}
+
+	msgs, err := c.Receive(context.Background())
+	if err != nil {
+		t.Fatalf("Receive() error: %v", err)
+	}
+	if len(msgs) != 1 {
+		t.Fatalf("got %d messages, want 1", len(msgs))
+	}
+	if msgs[0].ReceiveCount != 3 {
+		t.Errorf("ReceiveCount = %d, want 3", msgs[0].ReceiveCount)
+	}
+	if msgs[0].Body != body {
+		t.Errorf("Body = %q, want %q", msgs[0].Body, body)
+	}
+
+	if err := msgs[0].Ack(context.Background()); err != nil {
+		t.Fatalf("Ack() error: %v", err)
+	}
+	if len(fake.deleted) != 1 || fake.deleted[0] != receipt {
+		t.Errorf("DeleteMessage not called with receipt handle, got %v", fake.deleted)
+	}
+}
+
+func TestSQSConsumerReceiveDefaultsReceiveCount(t *testing.T) {
+	body := `{"order_id":"o1","customer_id":1}`
+	receipt := "receipt-2"
+	fake := &fakeSQS{
+		receiveOut: &sqs.ReceiveMessageOutput{
+			Messages: []sqstypes.Message{{Body: &body, ReceiptHandle: &receipt}},
+		},
+	}
+	c := &sqsConsumer{client: fake, queueURL: "q"}
+
+	msgs, err := c.Receive(context.Background())
+	if err != nil {
+		t.Fatalf("Receive() error: %v", err)
+	}
+	if msgs[0].ReceiveCount != 1 {
+		t.Errorf("ReceiveCount = %d, want 1 (default)", msgs[0].ReceiveCount)
+	}
+
+	if err := msgs[0].Extend(context.Background(), sqsVisibilityTimeout); err != nil {
+		t.Fatalf("Extend() error: %v", err)
+	}
+	if len(fake.extended) != 1 || fake.extended[0] != receipt {
+		t.Errorf("ChangeMessageVisibility not called with receipt handle, got %v", fake.extended)
+	}
+}